@@ -0,0 +1,17 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package auth contains common error types shared by authentication
+// backends (password, access token, SSH, OAuth2, ...).
+package auth
+
+// ErrBadCredentials indicates that the given credentials do not match any
+// known account.
+type ErrBadCredentials struct {
+	Args map[string]interface{}
+}
+
+func (err ErrBadCredentials) Error() string {
+	return "bad credentials"
+}
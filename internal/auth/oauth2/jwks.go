@@ -0,0 +1,90 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oauth2
+
+import (
+	"context"
+	"crypto/rsa"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	jwkset "github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+)
+
+// jwksVerifier verifies signed JWT access tokens against an issuer's JWKS,
+// refetching the key set at most once per cacheTTL.
+type jwksVerifier struct {
+	issuer   string
+	jwksURL  string
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	set       jwkset.Set
+	fetchedAt time.Time
+}
+
+func newJWKSVerifier(issuer, jwksURL string, cacheTTL time.Duration) *jwksVerifier {
+	return &jwksVerifier{
+		issuer:   issuer,
+		jwksURL:  jwksURL,
+		cacheTTL: cacheTTL,
+	}
+}
+
+func (v *jwksVerifier) Verify(ctx context.Context, rawToken string) (*Claims, error) {
+	keySet, err := v.keySet(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch jwks")
+	}
+
+	var claims jwt.MapClaims
+	_, err = jwt.ParseWithClaims(rawToken, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := keySet.LookupKeyID(kid)
+		if !ok {
+			return nil, errors.Errorf("unknown kid %q", kid)
+		}
+		var raw rsa.PublicKey
+		if err := key.Raw(&raw); err != nil {
+			return nil, errors.Wrap(err, "decode key")
+		}
+		return &raw, nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "parse token")
+	}
+
+	iss, _ := claims["iss"].(string)
+	if v.issuer != "" && iss != v.issuer {
+		return nil, errors.Errorf("unexpected issuer %q", iss)
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, errors.New("token has no sub claim")
+	}
+	scope, _ := claims["scope"].(string)
+
+	return &Claims{Issuer: iss, Subject: sub, Scope: scope}, nil
+}
+
+func (v *jwksVerifier) keySet(ctx context.Context) (jwkset.Set, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.set != nil && time.Since(v.fetchedAt) < v.cacheTTL {
+		return v.set, nil
+	}
+
+	set, err := jwkset.Fetch(ctx, v.jwksURL)
+	if err != nil {
+		return nil, err
+	}
+
+	v.set = set
+	v.fetchedAt = time.Now()
+	return v.set, nil
+}
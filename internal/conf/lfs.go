@@ -0,0 +1,51 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+import "time"
+
+// LFS contains the settings of LFS.
+var LFS = struct {
+	// JWTSecret is the HMAC secret used to sign and verify the short-lived
+	// tokens minted by `git-lfs-authenticate` for SSH-based LFS requests.
+	JWTSecret string `ini:"JWT_SECRET"`
+	// JWTExpiry is how long a `git-lfs-authenticate`-minted token remains
+	// valid for.
+	JWTExpiry time.Duration `ini:"JWT_EXPIRY"`
+
+	// OAuth2Issuer is the expected `iss` claim of bearer tokens accepted for
+	// LFS requests. When empty, the issuer of a bearer token is not checked.
+	OAuth2Issuer string `ini:"OAUTH2_ISSUER"`
+	// OAuth2JWKSURL is the issuer's JSON Web Key Set endpoint, used to verify
+	// signed JWT access tokens. Takes priority over OAuth2IntrospectionURL
+	// when both are set.
+	OAuth2JWKSURL string `ini:"OAUTH2_JWKS_URL"`
+	// OAuth2JWKSCacheTTL controls how long fetched keys are cached for.
+	OAuth2JWKSCacheTTL time.Duration `ini:"OAUTH2_JWKS_CACHE_TTL"`
+	// OAuth2IntrospectionURL is the issuer's RFC 7662 token introspection
+	// endpoint, used when the access token is opaque rather than a JWT.
+	OAuth2IntrospectionURL string `ini:"OAUTH2_INTROSPECTION_URL"`
+	// OAuth2IntrospectionClientID and OAuth2IntrospectionClientSecret
+	// authenticate this Gogs instance to the introspection endpoint.
+	OAuth2IntrospectionClientID     string `ini:"OAUTH2_INTROSPECTION_CLIENT_ID"`
+	OAuth2IntrospectionClientSecret string `ini:"OAUTH2_INTROSPECTION_CLIENT_SECRET"`
+	// OAuth2AutoProvision creates a local user on first successful bearer
+	// authentication when the subject claim has no matching external
+	// identity yet.
+	OAuth2AutoProvision bool `ini:"OAUTH2_AUTO_PROVISION"`
+
+	// AllowClientCertAuth enables resolving the requesting user from an
+	// enrolled mutual-TLS client certificate, bypassing Basic auth entirely.
+	AllowClientCertAuth bool `ini:"ALLOW_CLIENT_CERT_AUTH"`
+	// ClientCertIdentitySource selects which identity of the peer certificate
+	// is reported in diagnostics: "cn" (default) uses the Subject Common Name,
+	// "email" uses the first email SAN. The enrolled fingerprint is always
+	// looked up by the SHA-256 of the whole DER certificate.
+	ClientCertIdentitySource string `ini:"CLIENT_CERT_IDENTITY_SOURCE"`
+}{
+	JWTExpiry:                20 * time.Minute,
+	OAuth2JWKSCacheTTL:       10 * time.Minute,
+	ClientCertIdentitySource: "cn",
+}
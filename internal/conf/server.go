@@ -0,0 +1,12 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package conf
+
+// Server contains the settings of the web server.
+var Server = struct {
+	// ExternalURL is the externally accessible base URL of this instance,
+	// e.g. "https://gogs.example.com/". Always ends with a slash.
+	ExternalURL string `ini:"EXTERNAL_URL"`
+}{}
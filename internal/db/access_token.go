@@ -0,0 +1,148 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"sync"
+	"testing"
+)
+
+// AccessToken is a personal access token that can be used in place of a
+// password for HTTP(S) Git and API operations.
+type AccessToken struct {
+	ID     int64
+	UserID int64
+	Name   string
+	Sha1   string
+	// Scopes restricts what the token may be used for, e.g. "repo:read",
+	// "repo:write", "lfs:read", "lfs:write", "admin:org". A nil or empty
+	// slice is treated as unscoped, i.e. carrying the user's full account
+	// permissions, to preserve the behavior of tokens created before this
+	// column existed.
+	Scopes []string
+}
+
+// HasScope reports whether the token carries the given scope. An unscoped
+// token (no Scopes recorded) is treated as having every scope.
+func (t *AccessToken) HasScope(scope string) bool {
+	if len(t.Scopes) == 0 {
+		return true
+	}
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessTokensStore is the storage layer for personal access tokens.
+type AccessTokensStore interface {
+	// Create issues a new personal access token for the given user. A nil or
+	// empty scopes grants the token the user's full account permissions, to
+	// preserve the behavior of tokens created before Scopes existed.
+	Create(userID int64, name string, scopes []string) (*AccessToken, error)
+	// GetBySHA1 returns the access token with given SHA1, including its
+	// granted Scopes. It returns ErrAccessTokenNotExist when not found.
+	GetBySHA1(sha1 string) (*AccessToken, error)
+}
+
+var AccessTokens AccessTokensStore = &accessTokensStore{}
+
+type accessTokensStore struct{}
+
+func (*accessTokensStore) Create(userID int64, name string, scopes []string) (*AccessToken, error) {
+	panic("not implemented")
+}
+
+func (*accessTokensStore) GetBySHA1(sha1 string) (*AccessToken, error) {
+	panic("not implemented")
+}
+
+// SetMockAccessTokensStore sets the given mock as the AccessTokens store and
+// restores the original store when the test completes.
+func SetMockAccessTokensStore(t testing.TB, mock AccessTokensStore) {
+	if mock == nil {
+		return
+	}
+	before := AccessTokens
+	AccessTokens = mock
+	t.Cleanup(func() { AccessTokens = before })
+}
+
+// MockAccessTokensStore is a go-mockgen style mock of AccessTokensStore.
+type MockAccessTokensStore struct {
+	CreateFunc    *AccessTokensStoreCreateFunc
+	GetBySHA1Func *AccessTokensStoreGetBySHA1Func
+}
+
+// NewMockAccessTokensStore creates a new mock of AccessTokensStore with all
+// methods returning zero values until configured.
+func NewMockAccessTokensStore() *MockAccessTokensStore {
+	return &MockAccessTokensStore{
+		CreateFunc:    &AccessTokensStoreCreateFunc{},
+		GetBySHA1Func: &AccessTokensStoreGetBySHA1Func{},
+	}
+}
+
+func (m *MockAccessTokensStore) Create(userID int64, name string, scopes []string) (*AccessToken, error) {
+	return m.CreateFunc.nextHook()(userID, name, scopes)
+}
+
+func (m *MockAccessTokensStore) GetBySHA1(sha1 string) (*AccessToken, error) {
+	return m.GetBySHA1Func.nextHook()(sha1)
+}
+
+// AccessTokensStoreCreateFunc describes the behavior when the Create method
+// of the parent MockAccessTokensStore is invoked.
+type AccessTokensStoreCreateFunc struct {
+	mutex       sync.Mutex
+	defaultHook func(int64, string, []string) (*AccessToken, error)
+}
+
+func (f *AccessTokensStoreCreateFunc) SetDefaultHook(hook func(int64, string, []string) (*AccessToken, error)) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.defaultHook = hook
+}
+
+func (f *AccessTokensStoreCreateFunc) SetDefaultReturn(r0 *AccessToken, r1 error) {
+	f.SetDefaultHook(func(int64, string, []string) (*AccessToken, error) { return r0, r1 })
+}
+
+func (f *AccessTokensStoreCreateFunc) nextHook() func(int64, string, []string) (*AccessToken, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.defaultHook == nil {
+		return func(int64, string, []string) (*AccessToken, error) { return nil, nil }
+	}
+	return f.defaultHook
+}
+
+// AccessTokensStoreGetBySHA1Func describes the behavior when the GetBySHA1
+// method of the parent MockAccessTokensStore is invoked.
+type AccessTokensStoreGetBySHA1Func struct {
+	mutex       sync.Mutex
+	defaultHook func(string) (*AccessToken, error)
+}
+
+func (f *AccessTokensStoreGetBySHA1Func) SetDefaultHook(hook func(string) (*AccessToken, error)) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.defaultHook = hook
+}
+
+func (f *AccessTokensStoreGetBySHA1Func) SetDefaultReturn(r0 *AccessToken, r1 error) {
+	f.SetDefaultHook(func(string) (*AccessToken, error) { return r0, r1 })
+}
+
+func (f *AccessTokensStoreGetBySHA1Func) nextHook() func(string) (*AccessToken, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.defaultHook == nil {
+		return func(string) (*AccessToken, error) { return nil, nil }
+	}
+	return f.defaultHook
+}
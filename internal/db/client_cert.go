@@ -0,0 +1,107 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import "testing"
+
+// ClientCert is a client TLS certificate a user has enrolled for mutual-TLS
+// authentication, identified by the SHA-256 fingerprint of the certificate
+// rather than the certificate itself.
+type ClientCert struct {
+	ID          int64
+	UserID      int64
+	Label       string
+	Fingerprint string // Lower-case hex-encoded SHA-256 of the DER certificate.
+}
+
+// ClientCertsStore is the storage layer for enrolled client certificates.
+type ClientCertsStore interface {
+	// Create enrolls a new certificate fingerprint for the given user. The
+	// fingerprint is of the certificate's public content, so it must be
+	// unique across all users, not just within the enrolling user's own
+	// certificates; it returns ErrClientCertAlreadyExists if the fingerprint
+	// is already enrolled to any user.
+	Create(userID int64, label, fingerprint string) (*ClientCert, error)
+	// List returns all certificates enrolled by the given user.
+	List(userID int64) ([]*ClientCert, error)
+	// Delete removes the certificate with given ID owned by userID.
+	Delete(userID, id int64) error
+}
+
+var ClientCerts ClientCertsStore = &clientCertsStore{}
+
+type clientCertsStore struct{}
+
+func (*clientCertsStore) Create(userID int64, label, fingerprint string) (*ClientCert, error) {
+	panic("not implemented")
+}
+
+func (*clientCertsStore) List(userID int64) ([]*ClientCert, error) {
+	panic("not implemented")
+}
+
+func (*clientCertsStore) Delete(userID, id int64) error {
+	panic("not implemented")
+}
+
+// ErrClientCertNotExist indicates the enrolled certificate does not exist.
+type ErrClientCertNotExist struct {
+	Args map[string]interface{}
+}
+
+func IsErrClientCertNotExist(err error) bool {
+	_, ok := err.(ErrClientCertNotExist)
+	return ok
+}
+
+func (err ErrClientCertNotExist) Error() string {
+	return "client certificate does not exist"
+}
+
+// ErrClientCertAlreadyExists indicates the certificate fingerprint is already
+// enrolled, to this user or another one.
+type ErrClientCertAlreadyExists struct {
+	Args map[string]interface{}
+}
+
+func IsErrClientCertAlreadyExists(err error) bool {
+	_, ok := err.(ErrClientCertAlreadyExists)
+	return ok
+}
+
+func (err ErrClientCertAlreadyExists) Error() string {
+	return "client certificate already exists"
+}
+
+// MockClientCertsStore is a hand-rolled mock of ClientCertsStore for use in
+// tests.
+type MockClientCertsStore struct {
+	MockCreate func(userID int64, label, fingerprint string) (*ClientCert, error)
+	MockList   func(userID int64) ([]*ClientCert, error)
+	MockDelete func(userID, id int64) error
+}
+
+func (m *MockClientCertsStore) Create(userID int64, label, fingerprint string) (*ClientCert, error) {
+	return m.MockCreate(userID, label, fingerprint)
+}
+
+func (m *MockClientCertsStore) List(userID int64) ([]*ClientCert, error) {
+	return m.MockList(userID)
+}
+
+func (m *MockClientCertsStore) Delete(userID, id int64) error {
+	return m.MockDelete(userID, id)
+}
+
+// SetMockClientCertsStore sets the given mock as the ClientCerts store and
+// restores the original store when the test completes.
+func SetMockClientCertsStore(t testing.TB, mock *MockClientCertsStore) {
+	if mock == nil {
+		return
+	}
+	before := ClientCerts
+	ClientCerts = mock
+	t.Cleanup(func() { ClientCerts = before })
+}
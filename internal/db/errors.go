@@ -0,0 +1,49 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import "fmt"
+
+// ErrUserNotExist indicates the user does not exist.
+type ErrUserNotExist struct {
+	Args map[string]interface{}
+}
+
+func IsErrUserNotExist(err error) bool {
+	_, ok := err.(ErrUserNotExist)
+	return ok
+}
+
+func (err ErrUserNotExist) Error() string {
+	return fmt.Sprintf("user does not exist: %v", err.Args)
+}
+
+// ErrRepoNotExist indicates the repository does not exist.
+type ErrRepoNotExist struct {
+	Args map[string]interface{}
+}
+
+func IsErrRepoNotExist(err error) bool {
+	_, ok := err.(ErrRepoNotExist)
+	return ok
+}
+
+func (err ErrRepoNotExist) Error() string {
+	return fmt.Sprintf("repository does not exist: %v", err.Args)
+}
+
+// ErrAccessTokenNotExist indicates the access token does not exist.
+type ErrAccessTokenNotExist struct {
+	Args map[string]interface{}
+}
+
+func IsErrAccessTokenNotExist(err error) bool {
+	_, ok := err.(ErrAccessTokenNotExist)
+	return ok
+}
+
+func (err ErrAccessTokenNotExist) Error() string {
+	return fmt.Sprintf("access token does not exist: %v", err.Args)
+}
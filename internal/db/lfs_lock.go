@@ -0,0 +1,132 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// LFSLock represents a Git LFS file lock, see
+// https://github.com/git-lfs/git-lfs/blob/master/docs/api/locking.md.
+type LFSLock struct {
+	ID        int64
+	RepoID    int64
+	Path      string
+	OwnerID   int64
+	OwnerName string
+	LockedAt  time.Time
+}
+
+// LFSLocksStore is the storage layer for Git LFS file locks.
+type LFSLocksStore interface {
+	// Create creates a new lock on path within repoID held by ownerID. It
+	// returns ErrLFSLockAlreadyExists when path is already locked, enforcing
+	// the store's unique constraint on (repo_id, path).
+	Create(repoID int64, path string, ownerID int64, ownerName string) (*LFSLock, error)
+	// Get returns the lock on path within repoID. It returns
+	// ErrLFSLockNotExist when path is not locked.
+	Get(repoID int64, path string) (*LFSLock, error)
+	// GetByID returns the lock with given ID within repoID. It returns
+	// ErrLFSLockNotExist when not found.
+	GetByID(repoID, id int64) (*LFSLock, error)
+	// List returns all locks held within repoID.
+	List(repoID int64) ([]*LFSLock, error)
+	// Delete removes the lock with given ID within repoID and returns it.
+	// It returns ErrLFSLockNotExist when not found.
+	Delete(repoID, id int64) (*LFSLock, error)
+}
+
+var LFSLocks LFSLocksStore = &lfsLocksStore{}
+
+type lfsLocksStore struct{}
+
+func (*lfsLocksStore) Create(repoID int64, path string, ownerID int64, ownerName string) (*LFSLock, error) {
+	panic("not implemented")
+}
+
+func (*lfsLocksStore) Get(repoID int64, path string) (*LFSLock, error) {
+	panic("not implemented")
+}
+
+func (*lfsLocksStore) GetByID(repoID, id int64) (*LFSLock, error) {
+	panic("not implemented")
+}
+
+func (*lfsLocksStore) List(repoID int64) ([]*LFSLock, error) {
+	panic("not implemented")
+}
+
+func (*lfsLocksStore) Delete(repoID, id int64) (*LFSLock, error) {
+	panic("not implemented")
+}
+
+// ErrLFSLockAlreadyExists indicates the path is already locked.
+type ErrLFSLockAlreadyExists struct {
+	Args map[string]interface{}
+}
+
+func IsErrLFSLockAlreadyExists(err error) bool {
+	_, ok := err.(ErrLFSLockAlreadyExists)
+	return ok
+}
+
+func (err ErrLFSLockAlreadyExists) Error() string {
+	return "lock already exists"
+}
+
+// ErrLFSLockNotExist indicates the lock does not exist.
+type ErrLFSLockNotExist struct {
+	Args map[string]interface{}
+}
+
+func IsErrLFSLockNotExist(err error) bool {
+	_, ok := err.(ErrLFSLockNotExist)
+	return ok
+}
+
+func (err ErrLFSLockNotExist) Error() string {
+	return "lock does not exist"
+}
+
+// MockLFSLocksStore is a hand-rolled mock of LFSLocksStore for use in tests.
+type MockLFSLocksStore struct {
+	MockCreate  func(repoID int64, path string, ownerID int64, ownerName string) (*LFSLock, error)
+	MockGet     func(repoID int64, path string) (*LFSLock, error)
+	MockGetByID func(repoID, id int64) (*LFSLock, error)
+	MockList    func(repoID int64) ([]*LFSLock, error)
+	MockDelete  func(repoID, id int64) (*LFSLock, error)
+}
+
+func (m *MockLFSLocksStore) Create(repoID int64, path string, ownerID int64, ownerName string) (*LFSLock, error) {
+	return m.MockCreate(repoID, path, ownerID, ownerName)
+}
+
+func (m *MockLFSLocksStore) Get(repoID int64, path string) (*LFSLock, error) {
+	return m.MockGet(repoID, path)
+}
+
+func (m *MockLFSLocksStore) GetByID(repoID, id int64) (*LFSLock, error) {
+	return m.MockGetByID(repoID, id)
+}
+
+func (m *MockLFSLocksStore) List(repoID int64) ([]*LFSLock, error) {
+	return m.MockList(repoID)
+}
+
+func (m *MockLFSLocksStore) Delete(repoID, id int64) (*LFSLock, error) {
+	return m.MockDelete(repoID, id)
+}
+
+// SetMockLFSLocksStore sets the given mock as the LFSLocks store and
+// restores the original store when the test completes.
+func SetMockLFSLocksStore(t testing.TB, mock *MockLFSLocksStore) {
+	if mock == nil {
+		return
+	}
+	before := LFSLocks
+	LFSLocks = mock
+	t.Cleanup(func() { LFSLocks = before })
+}
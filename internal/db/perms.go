@@ -0,0 +1,98 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// AccessMode is the access mode a user has over a repository.
+type AccessMode int
+
+const (
+	AccessModeNone AccessMode = iota
+	AccessModeRead
+	AccessModeWrite
+	AccessModeAdmin
+	AccessModeOwner
+)
+
+// AccessModeOptions contains extra context used when computing a user's
+// effective access mode over a repository.
+type AccessModeOptions struct {
+	OwnerID int64
+	Private bool
+}
+
+// PermsStore is the storage layer for repository permissions.
+type PermsStore interface {
+	// Authorize returns true if the user with given userID has at least the
+	// desired access mode to the repository with given repoID.
+	Authorize(ctx context.Context, userID, repoID int64, desired AccessMode, opts AccessModeOptions) bool
+}
+
+var Perms PermsStore = &permsStore{}
+
+type permsStore struct{}
+
+func (*permsStore) Authorize(ctx context.Context, userID, repoID int64, desired AccessMode, opts AccessModeOptions) bool {
+	panic("not implemented")
+}
+
+// SetMockPermsStore sets the given mock as the Perms store and restores the
+// original store when the test completes.
+func SetMockPermsStore(t testing.TB, mock PermsStore) {
+	if mock == nil {
+		return
+	}
+	before := Perms
+	Perms = mock
+	t.Cleanup(func() { Perms = before })
+}
+
+// MockPermsStore is a go-mockgen style mock of PermsStore.
+type MockPermsStore struct {
+	AuthorizeFunc *PermsStoreAuthorizeFunc
+}
+
+// NewMockPermsStore creates a new mock of PermsStore with all methods
+// returning zero values until configured.
+func NewMockPermsStore() *MockPermsStore {
+	return &MockPermsStore{
+		AuthorizeFunc: &PermsStoreAuthorizeFunc{},
+	}
+}
+
+func (m *MockPermsStore) Authorize(ctx context.Context, userID, repoID int64, desired AccessMode, opts AccessModeOptions) bool {
+	return m.AuthorizeFunc.nextHook()(ctx, userID, repoID, desired, opts)
+}
+
+// PermsStoreAuthorizeFunc describes the behavior when the Authorize method of
+// the parent MockPermsStore is invoked.
+type PermsStoreAuthorizeFunc struct {
+	mutex       sync.Mutex
+	defaultHook func(context.Context, int64, int64, AccessMode, AccessModeOptions) bool
+}
+
+func (f *PermsStoreAuthorizeFunc) SetDefaultHook(hook func(context.Context, int64, int64, AccessMode, AccessModeOptions) bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.defaultHook = hook
+}
+
+func (f *PermsStoreAuthorizeFunc) SetDefaultReturn(r0 bool) {
+	f.SetDefaultHook(func(context.Context, int64, int64, AccessMode, AccessModeOptions) bool { return r0 })
+}
+
+func (f *PermsStoreAuthorizeFunc) nextHook() func(context.Context, int64, int64, AccessMode, AccessModeOptions) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.defaultHook == nil {
+		return func(context.Context, int64, int64, AccessMode, AccessModeOptions) bool { return false }
+	}
+	return f.defaultHook
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import "testing"
+
+// Repository represents a Gogs repository.
+type Repository struct {
+	ID        int64
+	OwnerID   int64
+	OwnerName string
+	Name      string
+}
+
+// FullName returns the owner/name path of the repository.
+func (r *Repository) FullName() string {
+	return r.OwnerName + "/" + r.Name
+}
+
+// ReposStore is the storage layer for repositories.
+type ReposStore interface {
+	// GetByName returns the repository with given owner ID and name. It
+	// returns ErrRepoNotExist when not found.
+	GetByName(ownerID int64, name string) (*Repository, error)
+}
+
+var Repos ReposStore = &reposStore{}
+
+type reposStore struct{}
+
+func (*reposStore) GetByName(ownerID int64, name string) (*Repository, error) {
+	panic("not implemented")
+}
+
+// MockReposStore is a hand-rolled mock of ReposStore for use in tests.
+type MockReposStore struct {
+	MockGetByName func(ownerID int64, name string) (*Repository, error)
+}
+
+func (m *MockReposStore) GetByName(ownerID int64, name string) (*Repository, error) {
+	return m.MockGetByName(ownerID, name)
+}
+
+// SetMockReposStore sets the given mock as the Repos store and restores the
+// original store when the test completes.
+func SetMockReposStore(t testing.TB, mock *MockReposStore) {
+	if mock == nil {
+		return
+	}
+	before := Repos
+	Repos = mock
+	t.Cleanup(func() { Repos = before })
+}
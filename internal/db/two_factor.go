@@ -0,0 +1,43 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import "testing"
+
+// TwoFactorsStore is the storage layer for two-factor authentication.
+type TwoFactorsStore interface {
+	// IsUserEnabled returns true if the user has two-factor authentication
+	// enabled.
+	IsUserEnabled(userID int64) bool
+}
+
+var TwoFactors TwoFactorsStore = &twoFactorsStore{}
+
+type twoFactorsStore struct{}
+
+func (*twoFactorsStore) IsUserEnabled(userID int64) bool {
+	panic("not implemented")
+}
+
+// MockTwoFactorsStore is a hand-rolled mock of TwoFactorsStore for use in
+// tests.
+type MockTwoFactorsStore struct {
+	MockIsUserEnabled func(userID int64) bool
+}
+
+func (m *MockTwoFactorsStore) IsUserEnabled(userID int64) bool {
+	return m.MockIsUserEnabled(userID)
+}
+
+// SetMockTwoFactorsStore sets the given mock as the TwoFactors store and
+// restores the original store when the test completes.
+func SetMockTwoFactorsStore(t testing.TB, mock *MockTwoFactorsStore) {
+	if mock == nil {
+		return
+	}
+	before := TwoFactors
+	TwoFactors = mock
+	t.Cleanup(func() { TwoFactors = before })
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package db
+
+import "testing"
+
+// User represents a Gogs user account.
+type User struct {
+	ID   int64
+	Name string
+
+	IsAdmin bool
+}
+
+// UsersStore is the storage layer for users.
+type UsersStore interface {
+	// Authenticate validates username and password via given login source ID.
+	// It returns ErrUserNotExist when the user was not found.
+	Authenticate(username, password string, loginSourceID int64) (*User, error)
+	// GetByID returns the user with given ID. It returns ErrUserNotExist when
+	// not found.
+	GetByID(id int64) (*User, error)
+	// GetByUsername returns the user with given username. It returns
+	// ErrUserNotExist when not found.
+	GetByUsername(username string) (*User, error)
+	// GetByExternalID returns the user linked to the given subject of an
+	// external identity provider (e.g. an OIDC issuer). It returns
+	// ErrUserNotExist when not found.
+	GetByExternalID(provider, subject string) (*User, error)
+	// CreateWithExternalID creates and links a new user to the given subject
+	// of an external identity provider, for auto-provisioning on first
+	// successful bearer authentication.
+	CreateWithExternalID(username, provider, subject string) (*User, error)
+	// GetByClientCertFingerprint returns the user who enrolled the client
+	// certificate with the given SHA-256 fingerprint. It returns
+	// ErrUserNotExist when not found.
+	GetByClientCertFingerprint(fingerprint string) (*User, error)
+}
+
+var Users UsersStore = &usersStore{}
+
+type usersStore struct{}
+
+func (*usersStore) Authenticate(username, password string, loginSourceID int64) (*User, error) {
+	panic("not implemented")
+}
+
+func (*usersStore) GetByID(id int64) (*User, error) {
+	panic("not implemented")
+}
+
+func (*usersStore) GetByUsername(username string) (*User, error) {
+	panic("not implemented")
+}
+
+func (*usersStore) GetByExternalID(provider, subject string) (*User, error) {
+	panic("not implemented")
+}
+
+func (*usersStore) CreateWithExternalID(username, provider, subject string) (*User, error) {
+	panic("not implemented")
+}
+
+func (*usersStore) GetByClientCertFingerprint(fingerprint string) (*User, error) {
+	panic("not implemented")
+}
+
+// MockUsersStore is a hand-rolled mock of UsersStore for use in tests.
+type MockUsersStore struct {
+	MockAuthenticate               func(username, password string, loginSourceID int64) (*User, error)
+	MockGetByID                    func(id int64) (*User, error)
+	MockGetByUsername              func(username string) (*User, error)
+	MockGetByExternalID            func(provider, subject string) (*User, error)
+	MockCreateWithExternalID       func(username, provider, subject string) (*User, error)
+	MockGetByClientCertFingerprint func(fingerprint string) (*User, error)
+}
+
+func (m *MockUsersStore) Authenticate(username, password string, loginSourceID int64) (*User, error) {
+	return m.MockAuthenticate(username, password, loginSourceID)
+}
+
+func (m *MockUsersStore) GetByID(id int64) (*User, error) {
+	return m.MockGetByID(id)
+}
+
+func (m *MockUsersStore) GetByUsername(username string) (*User, error) {
+	return m.MockGetByUsername(username)
+}
+
+func (m *MockUsersStore) GetByExternalID(provider, subject string) (*User, error) {
+	return m.MockGetByExternalID(provider, subject)
+}
+
+func (m *MockUsersStore) CreateWithExternalID(username, provider, subject string) (*User, error) {
+	return m.MockCreateWithExternalID(username, provider, subject)
+}
+
+func (m *MockUsersStore) GetByClientCertFingerprint(fingerprint string) (*User, error) {
+	return m.MockGetByClientCertFingerprint(fingerprint)
+}
+
+// SetMockUsersStore sets the given mock as the Users store and restores the
+// original store when the test completes. A nil mock leaves Users untouched,
+// mirroring how callers skip setting it when a test case doesn't need it.
+func SetMockUsersStore(t testing.TB, mock *MockUsersStore) {
+	if mock == nil {
+		return
+	}
+	before := Users
+	Users = mock
+	t.Cleanup(func() { Users = before })
+}
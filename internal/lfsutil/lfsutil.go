@@ -0,0 +1,24 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package lfsutil contains helper types and functions for Git LFS.
+package lfsutil
+
+import "regexp"
+
+// OID is the object ID of a Git LFS object, i.e. the SHA-256 checksum of its
+// content.
+type OID string
+
+var oidPattern = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+// IsValid returns true if the OID is a well-formed SHA-256 hex digest.
+func (oid OID) IsValid() bool {
+	return oidPattern.MatchString(string(oid))
+}
+
+// String returns the string representation of the OID.
+func (oid OID) String() string {
+	return string(oid)
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lfsutil
+
+import (
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+)
+
+// AuthTokenClaims are the claims embedded in a short-lived token minted by
+// `git-lfs-authenticate` for an SSH-authenticated LFS request.
+type AuthTokenClaims struct {
+	UserID    int64  `json:"user_id"`
+	RepoID    int64  `json:"repo_id"`
+	Operation string `json:"operation"`
+	jwt.StandardClaims
+}
+
+// NewAuthToken mints and signs a short-lived HMAC token authorizing userID
+// to perform operation ("download" or "upload") against repoID.
+func NewAuthToken(secret string, userID, repoID int64, operation string, expiry time.Duration) (string, error) {
+	now := time.Now()
+	claims := AuthTokenClaims{
+		UserID:    userID,
+		RepoID:    repoID,
+		Operation: operation,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(expiry).Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// ParseAuthToken verifies the signature and expiration of tokenString and
+// returns its claims.
+func ParseAuthToken(secret, tokenString string) (*AuthTokenClaims, error) {
+	if secret == "" {
+		return nil, errors.New("lfs.JWT_SECRET is not configured")
+	}
+
+	claims := &AuthTokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "parse token")
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package user implements user-facing API v1 endpoints that are not tied to
+// a specific repository.
+package user
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+
+	"gopkg.in/macaron.v1"
+
+	"gogs.io/gogs/internal/db"
+)
+
+// CreateClientCertOption is the request body of POST /user/client_certs.
+type CreateClientCertOption struct {
+	Label string `json:"label" binding:"Required"`
+	// Cert is the PEM-encoded client certificate to enroll. Only its SHA-256
+	// fingerprint is persisted.
+	Cert string `json:"cert" binding:"Required"`
+}
+
+// ListClientCerts implements GET /user/client_certs.
+func ListClientCerts(c *macaron.Context, actor *db.User) {
+	certs, err := db.ClientCerts.List(actor.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, certs)
+}
+
+// CreateClientCert implements POST /user/client_certs. It enrolls the
+// SHA-256 fingerprint of the submitted PEM certificate so the owner can
+// authenticate LFS requests over mutual TLS without shipping a password.
+func CreateClientCert(c *macaron.Context, actor *db.User, opt CreateClientCertOption) {
+	block, _ := pem.Decode([]byte(opt.Cert))
+	if block == nil {
+		c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid PEM certificate"})
+		return
+	}
+
+	// Reject anything that isn't actually a certificate (e.g. a PEM-encoded
+	// private key) before it gets hashed and enrolled as one.
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		c.JSON(http.StatusBadRequest, map[string]string{"message": "Invalid PEM certificate"})
+		return
+	}
+
+	fingerprint := sha256.Sum256(block.Bytes)
+	cert, err := db.ClientCerts.Create(actor.ID, opt.Label, hex.EncodeToString(fingerprint[:]))
+	if err != nil {
+		if db.IsErrClientCertAlreadyExists(err) {
+			c.JSON(http.StatusConflict, map[string]string{"message": "Certificate is already enrolled"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, cert)
+}
+
+// DeleteClientCert implements DELETE /user/client_certs/:id.
+func DeleteClientCert(c *macaron.Context, actor *db.User) {
+	id := c.ParamsInt64(":id")
+	if err := db.ClientCerts.Delete(actor.ID, id); err != nil {
+		if db.IsErrClientCertNotExist(err) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, map[string]string{"message": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,213 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lfs
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gopkg.in/macaron.v1"
+
+	"gogs.io/gogs/internal/db"
+)
+
+// decodeJSON decodes the request body into v.
+func decodeJSON(c *macaron.Context, v interface{}) error {
+	return json.NewDecoder(c.Req.Request.Body).Decode(v)
+}
+
+// lockOwner is the `owner` object embedded in a lock's JSON representation.
+type lockOwner struct {
+	Name string `json:"name"`
+}
+
+// lockJSON is the JSON representation of a lock, see
+// https://github.com/git-lfs/git-lfs/blob/master/docs/api/locking.md#lock-properties.
+type lockJSON struct {
+	ID       string    `json:"id"`
+	Path     string    `json:"path"`
+	LockedAt time.Time `json:"locked_at"`
+	Owner    lockOwner `json:"owner"`
+}
+
+func toLockJSON(lock *db.LFSLock) *lockJSON {
+	return &lockJSON{
+		ID:       strconv.FormatInt(lock.ID, 10),
+		Path:     lock.Path,
+		LockedAt: lock.LockedAt,
+		Owner:    lockOwner{Name: lock.OwnerName},
+	}
+}
+
+// createLockRequest is the request body of POST /locks.
+type createLockRequest struct {
+	Path string `json:"path"`
+}
+
+// createLockResponse is the response body of POST /locks.
+type createLockResponse struct {
+	Lock *lockJSON `json:"lock"`
+}
+
+// createLock implements POST /:username/:reponame/info/lfs/locks.
+func createLock(c *macaron.Context, repo *db.Repository) {
+	var req createLockRequest
+	if err := decodeJSON(c, &req); err != nil {
+		responseJSON(c.Resp, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	if req.Path == "" {
+		responseJSON(c.Resp, http.StatusUnprocessableEntity, map[string]string{"message": "path is required"})
+		return
+	}
+
+	who := actor(c)
+	lock, err := db.LFSLocks.Create(repo.ID, req.Path, who.ID, who.Name)
+	if err != nil {
+		if db.IsErrLFSLockAlreadyExists(err) {
+			existing, getErr := db.LFSLocks.Get(repo.ID, req.Path)
+			if getErr != nil {
+				internalServerError(c.Resp)
+				return
+			}
+			responseJSON(c.Resp, http.StatusConflict, map[string]interface{}{
+				"lock":    toLockJSON(existing),
+				"message": "already locked",
+			})
+			return
+		}
+		internalServerError(c.Resp)
+		return
+	}
+
+	responseJSON(c.Resp, http.StatusCreated, createLockResponse{Lock: toLockJSON(lock)})
+}
+
+// listLocksResponse is the response body of GET /locks.
+type listLocksResponse struct {
+	Locks      []*lockJSON `json:"locks"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// listLocks implements GET /:username/:reponame/info/lfs/locks.
+func listLocks(c *macaron.Context, repo *db.Repository) {
+	locks, err := db.LFSLocks.List(repo.ID)
+	if err != nil {
+		internalServerError(c.Resp)
+		return
+	}
+
+	path := c.Query("path")
+	resp := listLocksResponse{Locks: make([]*lockJSON, 0, len(locks))}
+	for _, lock := range locks {
+		if path != "" && lock.Path != path {
+			continue
+		}
+		resp.Locks = append(resp.Locks, toLockJSON(lock))
+	}
+	responseJSON(c.Resp, http.StatusOK, resp)
+}
+
+// verifyLocksRequest is the request body of POST /locks/verify.
+type verifyLocksRequest struct {
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+}
+
+// verifyLocksResponse is the response body of POST /locks/verify.
+type verifyLocksResponse struct {
+	Ours   []*lockJSON `json:"ours"`
+	Theirs []*lockJSON `json:"theirs"`
+}
+
+// verifyLocks implements POST /:username/:reponame/info/lfs/locks/verify. It
+// reports which locks belong to the requesting actor ("ours") versus someone
+// else ("theirs"), so the client knows which files it may push.
+func verifyLocks(c *macaron.Context, repo *db.Repository) {
+	var req verifyLocksRequest
+	if err := decodeJSON(c, &req); err != nil {
+		responseJSON(c.Resp, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	locks, err := db.LFSLocks.List(repo.ID)
+	if err != nil {
+		internalServerError(c.Resp)
+		return
+	}
+
+	who := actor(c)
+	resp := verifyLocksResponse{Ours: []*lockJSON{}, Theirs: []*lockJSON{}}
+	for _, lock := range locks {
+		if lock.OwnerID == who.ID {
+			resp.Ours = append(resp.Ours, toLockJSON(lock))
+		} else {
+			resp.Theirs = append(resp.Theirs, toLockJSON(lock))
+		}
+	}
+	responseJSON(c.Resp, http.StatusOK, resp)
+}
+
+// unlockRequest is the request body of POST /locks/:id/unlock.
+type unlockRequest struct {
+	Force bool `json:"force"`
+}
+
+// unlockResponse is the response body of POST /locks/:id/unlock.
+type unlockResponse struct {
+	Lock *lockJSON `json:"lock"`
+}
+
+// unlock implements POST /:username/:reponame/info/lfs/locks/:id/unlock. The
+// `authorize` middleware in front of this route is wired with
+// AccessModeWrite, since that's enough to delete one's own lock; it has no
+// way to see the `force` flag, which only arrives in the request body. So a
+// forced unlock of someone else's lock is authorized here, directly against
+// db.Perms and scopeAllows, with the desired mode bumped to AccessModeAdmin —
+// re-checking scopeAllows matters because a RemoteAuth or access-token scope
+// that only covers AccessModeWrite must not be allowed to reach for admin
+// just because the route itself was wired with a lower mode.
+func unlock(c *macaron.Context, owner *db.User, repo *db.Repository) {
+	id, err := strconv.ParseInt(c.Params(":id"), 10, 64)
+	if err != nil {
+		responseJSON(c.Resp, http.StatusNotFound, map[string]string{"message": "lock does not exist"})
+		return
+	}
+
+	var req unlockRequest
+	_ = decodeJSON(c, &req)
+
+	lock, err := db.LFSLocks.GetByID(repo.ID, id)
+	if err != nil {
+		if db.IsErrLFSLockNotExist(err) {
+			responseJSON(c.Resp, http.StatusNotFound, map[string]string{"message": "lock does not exist"})
+			return
+		}
+		internalServerError(c.Resp)
+		return
+	}
+
+	who := actor(c)
+	if lock.OwnerID != who.ID {
+		if !req.Force {
+			responseJSON(c.Resp, http.StatusForbidden, map[string]string{"message": "not authorized to unlock"})
+			return
+		}
+		if !scopeAllows(c, repo, db.AccessModeAdmin) || !db.Perms.Authorize(c.Req.Context(), who.ID, repo.ID, db.AccessModeAdmin, db.AccessModeOptions{OwnerID: owner.ID}) {
+			responseJSON(c.Resp, http.StatusForbidden, map[string]string{"message": "force unlock requires admin access"})
+			return
+		}
+	}
+
+	lock, err = db.LFSLocks.Delete(repo.ID, id)
+	if err != nil {
+		internalServerError(c.Resp)
+		return
+	}
+
+	responseJSON(c.Resp, http.StatusOK, unlockResponse{Lock: toLockJSON(lock)})
+}
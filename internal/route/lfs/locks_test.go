@@ -0,0 +1,319 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lfs
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/macaron.v1"
+
+	"gogs.io/gogs/internal/db"
+)
+
+// withActorAndRepo maps a fake actor, owner and repo into the context ahead
+// of a locks handler under test, standing in for authenticate+authorize.
+func withActorAndRepo(actor *db.User, owner *db.User, repo *db.Repository) macaron.Handler {
+	return func(c *macaron.Context) {
+		mapActor(c, actor)
+		c.Map(owner)
+		c.Map(repo)
+	}
+}
+
+func Test_createLock(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		mockLocksStore  *db.MockLFSLocksStore
+		expStatusCode   int
+		expBodyContains string
+	}{
+		{
+			name:          "invalid JSON body",
+			body:          "{",
+			expStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:          "missing path",
+			body:          `{"path": ""}`,
+			expStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "path already locked",
+			body: `{"path": "a.bin"}`,
+			mockLocksStore: &db.MockLFSLocksStore{
+				MockCreate: func(repoID int64, path string, ownerID int64, ownerName string) (*db.LFSLock, error) {
+					return nil, db.ErrLFSLockAlreadyExists{}
+				},
+				MockGet: func(repoID int64, path string) (*db.LFSLock, error) {
+					return &db.LFSLock{ID: 1, RepoID: repoID, Path: path, OwnerID: 2, OwnerName: "other"}, nil
+				},
+			},
+			expStatusCode:   http.StatusConflict,
+			expBodyContains: `"already locked"`,
+		},
+		{
+			name: "successful create",
+			body: `{"path": "a.bin"}`,
+			mockLocksStore: &db.MockLFSLocksStore{
+				MockCreate: func(repoID int64, path string, ownerID int64, ownerName string) (*db.LFSLock, error) {
+					return &db.LFSLock{ID: 1, RepoID: repoID, Path: path, OwnerID: ownerID, OwnerName: ownerName}, nil
+				},
+			},
+			expStatusCode:   http.StatusCreated,
+			expBodyContains: `"path":"a.bin"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db.SetMockLFSLocksStore(t, test.mockLocksStore)
+
+			m := macaron.New()
+			m.Use(macaron.Renderer())
+			m.Post("/", withActorAndRepo(&db.User{ID: 1, Name: "alice"}, &db.User{ID: 1, Name: "alice"}, &db.Repository{ID: 10}), createLock)
+
+			r, err := http.NewRequest("POST", "/", strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			m.ServeHTTP(rr, r)
+
+			resp := rr.Result()
+			assert.Equal(t, test.expStatusCode, resp.StatusCode)
+
+			if test.expBodyContains != "" {
+				body, err := ioutil.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Contains(t, string(body), test.expBodyContains)
+			}
+		})
+	}
+}
+
+func Test_listLocks(t *testing.T) {
+	db.SetMockLFSLocksStore(t, &db.MockLFSLocksStore{
+		MockList: func(repoID int64) ([]*db.LFSLock, error) {
+			return []*db.LFSLock{
+				{ID: 1, RepoID: repoID, Path: "a.bin", OwnerID: 1, OwnerName: "alice"},
+				{ID: 2, RepoID: repoID, Path: "b.bin", OwnerID: 2, OwnerName: "bob"},
+			}, nil
+		},
+	})
+
+	m := macaron.New()
+	m.Use(macaron.Renderer())
+	m.Get("/", withActorAndRepo(&db.User{ID: 1, Name: "alice"}, &db.User{ID: 1, Name: "alice"}, &db.Repository{ID: 10}), listLocks)
+
+	r, err := http.NewRequest("GET", "/?path=b.bin", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	m.ServeHTTP(rr, r)
+
+	resp := rr.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(body), `"path":"b.bin"`)
+	assert.NotContains(t, string(body), `"path":"a.bin"`)
+}
+
+func Test_verifyLocks(t *testing.T) {
+	db.SetMockLFSLocksStore(t, &db.MockLFSLocksStore{
+		MockList: func(repoID int64) ([]*db.LFSLock, error) {
+			return []*db.LFSLock{
+				{ID: 1, RepoID: repoID, Path: "a.bin", OwnerID: 1, OwnerName: "alice"},
+				{ID: 2, RepoID: repoID, Path: "b.bin", OwnerID: 2, OwnerName: "bob"},
+			}, nil
+		},
+	})
+
+	m := macaron.New()
+	m.Use(macaron.Renderer())
+	m.Post("/", withActorAndRepo(&db.User{ID: 1, Name: "alice"}, &db.User{ID: 1, Name: "alice"}, &db.Repository{ID: 10}), verifyLocks)
+
+	r, err := http.NewRequest("POST", "/", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	m.ServeHTTP(rr, r)
+
+	resp := rr.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(body), `"ours":[{"id":"1","path":"a.bin"`)
+	assert.Contains(t, string(body), `"theirs":[{"id":"2","path":"b.bin"`)
+}
+
+func Test_unlock(t *testing.T) {
+	const repoID = int64(10)
+
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		actorID        int64
+		mapScope       macaron.Handler
+		mockLocksStore *db.MockLFSLocksStore
+		mockPermsStore func() db.PermsStore
+		expStatusCode  int
+	}{
+		{
+			name:          "invalid id",
+			id:            "not-a-number",
+			body:          `{}`,
+			expStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "lock does not exist",
+			id:   "1",
+			body: `{}`,
+			mockLocksStore: &db.MockLFSLocksStore{
+				MockGetByID: func(repoID, id int64) (*db.LFSLock, error) {
+					return nil, db.ErrLFSLockNotExist{}
+				},
+			},
+			expStatusCode: http.StatusNotFound,
+		},
+		{
+			name:    "owner unlocks own lock without force",
+			id:      "1",
+			body:    `{}`,
+			actorID: 1,
+			mockLocksStore: &db.MockLFSLocksStore{
+				MockGetByID: func(repoID, id int64) (*db.LFSLock, error) {
+					return &db.LFSLock{ID: id, RepoID: repoID, Path: "a.bin", OwnerID: 1, OwnerName: "alice"}, nil
+				},
+				MockDelete: func(repoID, id int64) (*db.LFSLock, error) {
+					return &db.LFSLock{ID: id, RepoID: repoID, Path: "a.bin", OwnerID: 1, OwnerName: "alice"}, nil
+				},
+			},
+			expStatusCode: http.StatusOK,
+		},
+		{
+			name:    "non-owner without force is rejected",
+			id:      "1",
+			body:    `{}`,
+			actorID: 2,
+			mockLocksStore: &db.MockLFSLocksStore{
+				MockGetByID: func(repoID, id int64) (*db.LFSLock, error) {
+					return &db.LFSLock{ID: id, RepoID: repoID, Path: "a.bin", OwnerID: 1, OwnerName: "alice"}, nil
+				},
+			},
+			expStatusCode: http.StatusForbidden,
+		},
+		{
+			name:    "non-owner force without admin permission is rejected",
+			id:      "1",
+			body:    `{"force": true}`,
+			actorID: 2,
+			mockLocksStore: &db.MockLFSLocksStore{
+				MockGetByID: func(repoID, id int64) (*db.LFSLock, error) {
+					return &db.LFSLock{ID: id, RepoID: repoID, Path: "a.bin", OwnerID: 1, OwnerName: "alice"}, nil
+				},
+			},
+			mockPermsStore: func() db.PermsStore {
+				mock := db.NewMockPermsStore()
+				mock.AuthorizeFunc.SetDefaultHook(func(ctx context.Context, userID int64, repoID int64, desired db.AccessMode, opts db.AccessModeOptions) bool {
+					return desired <= db.AccessModeWrite
+				})
+				return mock
+			},
+			expStatusCode: http.StatusForbidden,
+		},
+		{
+			name:    "non-owner force with admin permission succeeds",
+			id:      "1",
+			body:    `{"force": true}`,
+			actorID: 2,
+			mockLocksStore: &db.MockLFSLocksStore{
+				MockGetByID: func(repoID, id int64) (*db.LFSLock, error) {
+					return &db.LFSLock{ID: id, RepoID: repoID, Path: "a.bin", OwnerID: 1, OwnerName: "alice"}, nil
+				},
+				MockDelete: func(repoID, id int64) (*db.LFSLock, error) {
+					return &db.LFSLock{ID: id, RepoID: repoID, Path: "a.bin", OwnerID: 1, OwnerName: "alice"}, nil
+				},
+			},
+			mockPermsStore: func() db.PermsStore {
+				mock := db.NewMockPermsStore()
+				mock.AuthorizeFunc.SetDefaultReturn(true)
+				return mock
+			},
+			expStatusCode: http.StatusOK,
+		},
+		{
+			// Guards the fix for the force-unlock scope bypass: a request
+			// authenticated via a RemoteAuth token scoped only to "upload"
+			// must not be able to force-unlock just because the underlying
+			// account happens to have real admin permission on the repo.
+			name:    "non-owner force with admin permission but token scoped to upload only is rejected",
+			id:      "1",
+			body:    `{"force": true}`,
+			actorID: 2,
+			mapScope: func(c *macaron.Context) {
+				c.Map(&tokenScope{repoID: repoID, operation: "upload"})
+			},
+			mockLocksStore: &db.MockLFSLocksStore{
+				MockGetByID: func(repoID, id int64) (*db.LFSLock, error) {
+					return &db.LFSLock{ID: id, RepoID: repoID, Path: "a.bin", OwnerID: 1, OwnerName: "alice"}, nil
+				},
+			},
+			mockPermsStore: func() db.PermsStore {
+				mock := db.NewMockPermsStore()
+				mock.AuthorizeFunc.SetDefaultReturn(true)
+				return mock
+			},
+			expStatusCode: http.StatusForbidden,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db.SetMockLFSLocksStore(t, test.mockLocksStore)
+			if test.mockPermsStore != nil {
+				db.SetMockPermsStore(t, test.mockPermsStore())
+			}
+
+			owner := &db.User{ID: 1, Name: "owner"}
+			m := macaron.New()
+			m.Use(macaron.Renderer())
+			m.Use(withActorAndRepo(&db.User{ID: test.actorID}, owner, &db.Repository{ID: repoID}))
+			if test.mapScope != nil {
+				m.Use(test.mapScope)
+			}
+			m.Post("/:id/unlock", unlock)
+
+			r, err := http.NewRequest("POST", "/"+test.id+"/unlock", strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			m.ServeHTTP(rr, r)
+
+			assert.Equal(t, test.expStatusCode, rr.Result().StatusCode)
+		})
+	}
+}
@@ -0,0 +1,417 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package lfs implements the Git LFS HTTP batch API, see
+// https://github.com/git-lfs/git-lfs/blob/master/docs/api/README.md.
+package lfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gopkg.in/macaron.v1"
+	log "unknwon.dev/clog/v2"
+
+	"gogs.io/gogs/internal/auth/oauth2"
+	"gogs.io/gogs/internal/conf"
+	"gogs.io/gogs/internal/db"
+	"gogs.io/gogs/internal/lfsutil"
+)
+
+// contentType is the Content-Type all LFS API responses are served with, per
+// https://github.com/git-lfs/git-lfs/blob/master/docs/api/README.md#requests.
+const contentType = "application/vnd.git-lfs+json"
+
+// responseJSON writes v as a JSON response with the LFS content type and
+// given status code.
+func responseJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// requireCredentials responds with a 401 asking the client to retry with
+// HTTP Basic credentials.
+func requireCredentials(w http.ResponseWriter) {
+	w.Header().Set("Lfs-Authenticate", `Basic realm="Git LFS"`)
+	responseJSON(w, http.StatusUnauthorized, map[string]string{"message": "Credentials needed"})
+}
+
+// internalServerError responds with a generic 500 in the LFS error envelope.
+func internalServerError(w http.ResponseWriter) {
+	responseJSON(w, http.StatusInternalServerError, map[string]string{"message": "Internal server error"})
+}
+
+// tokenScope carries the repository and operation a short-lived LFS token
+// (minted for an SSH `git-lfs-authenticate` request) was granted for, so
+// authorize can reject an operation the token itself was never scoped to,
+// independent of the actor's general repository permission. authorize
+// enforces both dimensions: the token must have been minted for the
+// repository being accessed, and its operation must cover the desired
+// access mode.
+type tokenScope struct {
+	repoID    int64
+	operation string
+}
+
+// allows reports whether the token scope permits the desired access mode.
+func (s *tokenScope) allows(desired db.AccessMode) bool {
+	if s.operation == "upload" {
+		return desired <= db.AccessModeWrite
+	}
+	return desired <= db.AccessModeRead
+}
+
+// accessTokenScopes carries the scopes of a personal access token used to
+// authenticate an LFS request, so authorize can reject an operation the
+// token itself was never granted, independent of the actor's general
+// repository permission. An empty accessTokenScopes is unscoped and allows
+// everything, matching db.AccessToken.HasScope.
+type accessTokenScopes []string
+
+// allows reports whether the token's scopes permit the desired access mode.
+func (s accessTokenScopes) allows(desired db.AccessMode) bool {
+	if len(s) == 0 {
+		return true
+	}
+	token := db.AccessToken{Scopes: s}
+	if desired <= db.AccessModeRead {
+		return token.HasScope("lfs:read") || token.HasScope("lfs:write")
+	}
+	return token.HasScope("lfs:write")
+}
+
+// actorDataKey is the c.Data key under which authenticate stores the
+// authenticated actor. authorize also maps the resolved owner as a *db.User,
+// which would otherwise collide with the actor under macaron's type-based
+// injection, so downstream handlers that need both read the actor back via
+// this key instead of a function parameter.
+const actorDataKey = "lfs.actor"
+
+// mapActor records user as both the macaron-injected *db.User (for handlers
+// that only need the authenticated user, as in Test_authenticate) and the
+// context-keyed actor (for handlers downstream of authorize, which also maps
+// a *db.User for the repository owner).
+func mapActor(c *macaron.Context, user *db.User) {
+	c.Map(user)
+	c.Data[actorDataKey] = user
+}
+
+// actor returns the actor mapped by authenticate, or nil if none was mapped
+// (e.g. in tests that drive authorize directly).
+func actor(c *macaron.Context) *db.User {
+	user, _ := c.Data[actorDataKey].(*db.User)
+	return user
+}
+
+// authenticate resolves the requesting *db.User from the Authorization
+// header and maps it into the context. It supports HTTP Basic credentials
+// (username/password or a personal access token used as the username), the
+// `RemoteAuth` scheme used by `git-lfs-authenticate` over SSH, and OAuth2/OIDC
+// `Bearer` tokens. When client certificate authentication is enabled and the
+// request carries a TLS peer certificate, the certificate alone decides and
+// the Authorization header is not consulted.
+func authenticate() macaron.Handler {
+	return func(c *macaron.Context) {
+		if conf.LFS.AllowClientCertAuth && c.Req.TLS != nil && len(c.Req.TLS.PeerCertificates) > 0 {
+			cert := c.Req.TLS.PeerCertificates[0]
+			user, err := authenticateClientCert(cert)
+			if err != nil {
+				log.Trace("LFS: client certificate authentication failed for %q: %v", clientCertIdentity(cert), err)
+				requireCredentials(c.Resp)
+				return
+			}
+			mapActor(c, user)
+			return
+		}
+
+		header := c.Req.Header.Get("Authorization")
+		if header == "" {
+			requireCredentials(c.Resp)
+			return
+		}
+
+		fields := strings.SplitN(header, " ", 2)
+		if len(fields) != 2 {
+			requireCredentials(c.Resp)
+			return
+		}
+		scheme, value := fields[0], fields[1]
+
+		switch scheme {
+		case "RemoteAuth":
+			user, scope, err := authenticateRemoteAuth(value)
+			if err != nil {
+				log.Trace("LFS: RemoteAuth authentication failed: %v", err)
+				requireCredentials(c.Resp)
+				return
+			}
+			mapActor(c, user)
+			c.Map(scope)
+			return
+
+		case "Basic":
+			raw, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				requireCredentials(c.Resp)
+				return
+			}
+
+			creds := strings.SplitN(string(raw), ":", 2)
+			username := creds[0]
+			var password string
+			if len(creds) == 2 {
+				password = creds[1]
+			}
+
+			user, err := db.Users.Authenticate(username, password, 0)
+			if err == nil {
+				if db.TwoFactors.IsUserEnabled(user.ID) {
+					c.Resp.WriteHeader(http.StatusBadRequest)
+					_, _ = c.Resp.Write([]byte("Users with 2FA enabled are not allowed to authenticate via username and password."))
+					return
+				}
+				mapActor(c, user)
+				return
+			}
+
+			// Not a valid username/password pair, fall back to treating the
+			// username field as a personal access token.
+			token, err := db.AccessTokens.GetBySHA1(username)
+			if err != nil {
+				requireCredentials(c.Resp)
+				return
+			}
+
+			user, err = db.Users.GetByID(token.UserID)
+			if err != nil {
+				internalServerError(c.Resp)
+				return
+			}
+			mapActor(c, user)
+			c.Map(accessTokenScopes(token.Scopes))
+			return
+
+		case "Bearer":
+			user, err := authenticateBearer(c.Req.Context(), value)
+			if err != nil {
+				log.Trace("LFS: bearer authentication failed: %v", err)
+				requireCredentials(c.Resp)
+				return
+			}
+			mapActor(c, user)
+			return
+
+		default:
+			requireCredentials(c.Resp)
+			return
+		}
+	}
+}
+
+// authenticateRemoteAuth verifies a `git-lfs-authenticate`-minted token and
+// resolves the user and operation scope it was issued for.
+func authenticateRemoteAuth(token string) (*db.User, *tokenScope, error) {
+	claims, err := lfsutil.ParseAuthToken(conf.LFS.JWTSecret, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := db.Users.GetByID(claims.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, &tokenScope{repoID: claims.RepoID, operation: claims.Operation}, nil
+}
+
+// authenticateClientCert resolves the user who enrolled cert's SHA-256
+// fingerprint. The fingerprint is computed over the raw DER bytes, so it
+// identifies this exact certificate rather than its claimed identity; the CN
+// or SAN email is only used for operator-facing error messages.
+func authenticateClientCert(cert *x509.Certificate) (*db.User, error) {
+	fingerprint := sha256.Sum256(cert.Raw)
+	return db.Users.GetByClientCertFingerprint(hex.EncodeToString(fingerprint[:]))
+}
+
+// clientCertIdentity returns the identity claimed by cert according to the
+// configured [lfs] CLIENT_CERT_IDENTITY_SOURCE, for diagnostics only.
+func clientCertIdentity(cert *x509.Certificate) string {
+	if conf.LFS.ClientCertIdentitySource == "email" && len(cert.EmailAddresses) > 0 {
+		return cert.EmailAddresses[0]
+	}
+	return cert.Subject.CommonName
+}
+
+const oauth2Provider = "oauth2"
+
+var (
+	oauth2VerifierMu     sync.Mutex
+	oauth2VerifierCached oauth2.Verifier
+
+	// newOAuth2Verifier builds the configured OAuth2 bearer token verifier.
+	// Overridden in tests to avoid hitting a real issuer.
+	newOAuth2Verifier = func() (oauth2.Verifier, error) {
+		return oauth2.NewVerifier(oauth2.Config{
+			Issuer:                    conf.LFS.OAuth2Issuer,
+			JWKSURL:                   conf.LFS.OAuth2JWKSURL,
+			JWKSCacheTTL:              conf.LFS.OAuth2JWKSCacheTTL,
+			IntrospectionURL:          conf.LFS.OAuth2IntrospectionURL,
+			IntrospectionClientID:     conf.LFS.OAuth2IntrospectionClientID,
+			IntrospectionClientSecret: conf.LFS.OAuth2IntrospectionClientSecret,
+		})
+	}
+)
+
+// getOAuth2Verifier lazily builds and caches the OAuth2 bearer token
+// verifier, since a JWKS verifier keeps its own internal key cache that
+// should be reused across requests.
+func getOAuth2Verifier() (oauth2.Verifier, error) {
+	oauth2VerifierMu.Lock()
+	defer oauth2VerifierMu.Unlock()
+	if oauth2VerifierCached != nil {
+		return oauth2VerifierCached, nil
+	}
+	verifier, err := newOAuth2Verifier()
+	if err != nil {
+		return nil, err
+	}
+	oauth2VerifierCached = verifier
+	return oauth2VerifierCached, nil
+}
+
+// authenticateBearer verifies an OAuth2/OIDC access token and resolves it to
+// a local user, auto-provisioning one if configured.
+func authenticateBearer(ctx context.Context, rawToken string) (*db.User, error) {
+	verifier, err := getOAuth2Verifier()
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := db.Users.GetByExternalID(oauth2Provider, claims.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !db.IsErrUserNotExist(err) || !conf.LFS.OAuth2AutoProvision {
+		return nil, err
+	}
+
+	return db.Users.CreateWithExternalID(claims.Subject, oauth2Provider, claims.Subject)
+}
+
+// scopeAllows reports whether a tokenScope or accessTokenScopes mapped by
+// authenticate for this request (i.e. the request was authenticated via an
+// SSH-minted token or a scoped personal access token, rather than a
+// password) permits the desired access mode against repo. It reports true
+// when neither was mapped, since password-authenticated requests carry no
+// scope restriction beyond the actor's account-wide permission. Callers that
+// need to check an access mode other than the one authorize was wired with —
+// such as unlock's force path, which requires admin regardless of the route's
+// AccessModeWrite — call this directly instead of relying on authorize.
+func scopeAllows(c *macaron.Context, repo *db.Repository, desired db.AccessMode) bool {
+	if v := c.GetVal(reflect.TypeOf(&tokenScope{})); v.IsValid() {
+		if scope, ok := v.Interface().(*tokenScope); ok && scope != nil && (scope.repoID != repo.ID || !scope.allows(desired)) {
+			return false
+		}
+	}
+
+	if v := c.GetVal(reflect.TypeOf(accessTokenScopes{})); v.IsValid() {
+		if scopes, ok := v.Interface().(accessTokenScopes); ok && !scopes.allows(desired) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// authorize returns a middleware that resolves the :username/:reponame route
+// parameters into an owner and repository, and requires the authenticated
+// actor to have at least the desired access mode. When authenticate mapped a
+// tokenScope or accessTokenScopes (i.e. the request was authenticated via an
+// SSH-minted token or a scoped personal access token, rather than a
+// password), the desired access mode must also fall within that scope. It
+// responds 404 rather than 403 when the owner or repository does not exist
+// or access is denied, so as to not leak the existence of private
+// repositories; other store errors respond 500.
+func authorize(desired db.AccessMode) macaron.Handler {
+	return func(c *macaron.Context) {
+		username := c.Params(":username")
+		reponame := c.Params(":reponame")
+
+		owner, err := db.Users.GetByUsername(username)
+		if err != nil {
+			if db.IsErrUserNotExist(err) {
+				c.Status(http.StatusNotFound)
+				return
+			}
+			internalServerError(c.Resp)
+			return
+		}
+
+		repo, err := db.Repos.GetByName(owner.ID, reponame)
+		if err != nil {
+			if db.IsErrRepoNotExist(err) {
+				c.Status(http.StatusNotFound)
+				return
+			}
+			internalServerError(c.Resp)
+			return
+		}
+
+		var actorID int64
+		if a := actor(c); a != nil {
+			actorID = a.ID
+		}
+
+		if !db.Perms.Authorize(c.Req.Context(), actorID, repo.ID, desired, db.AccessModeOptions{OwnerID: owner.ID}) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+
+		if !scopeAllows(c, repo, desired) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+
+		c.Map(owner)
+		c.Map(repo)
+	}
+}
+
+// verifyHeader returns a middleware that requires the request to carry the
+// given header with the expected value, responding with statusCode
+// otherwise. Values are compared as a prefix match so parameters such as
+// `; charset=utf-8` don't cause false negatives.
+func verifyHeader(header, expected string, statusCode int) macaron.Handler {
+	return func(c *macaron.Context) {
+		if !strings.HasPrefix(c.Req.Header.Get(header), expected) {
+			c.Status(statusCode)
+		}
+	}
+}
+
+// verifyOID returns a middleware that validates the `:oid` route parameter
+// and maps it as a lfsutil.OID.
+func verifyOID() macaron.Handler {
+	return func(c *macaron.Context) {
+		oid := lfsutil.OID(c.Params(":oid"))
+		if !oid.IsValid() {
+			responseJSON(c.Resp, http.StatusBadRequest, map[string]string{"message": "Invalid oid"})
+			return
+		}
+		c.Map(oid)
+	}
+}
@@ -6,16 +6,26 @@ package lfs
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/macaron.v1"
 
 	"gogs.io/gogs/internal/auth"
+	"gogs.io/gogs/internal/auth/oauth2"
+	"gogs.io/gogs/internal/conf"
 	"gogs.io/gogs/internal/db"
 	"gogs.io/gogs/internal/lfsutil"
 )
@@ -161,6 +171,398 @@ func Test_authenticate(t *testing.T) {
 	}
 }
 
+// Test_authenticate_RemoteAuth covers the `RemoteAuth` scheme minted by
+// `git-lfs-authenticate` for SSH-based LFS requests, in addition to the
+// Basic-auth cases covered by Test_authenticate.
+func Test_authenticate_RemoteAuth(t *testing.T) {
+	conf.LFS.JWTSecret = "super-secret"
+	defer func() { conf.LFS.JWTSecret = "" }()
+
+	m := macaron.New()
+	m.Use(macaron.Renderer())
+	m.Get("/", authenticate(), func(w http.ResponseWriter, user *db.User) {
+		fmt.Fprintf(w, "ID: %d, Name: %s", user.ID, user.Name)
+	})
+
+	tests := []struct {
+		name           string
+		token          func() string
+		mockUsersStore *db.MockUsersStore
+		expStatusCode  int
+	}{
+		{
+			name: "valid token",
+			token: func() string {
+				token, _ := lfsutil.NewAuthToken("super-secret", 1, 2, "download", time.Hour)
+				return token
+			},
+			mockUsersStore: &db.MockUsersStore{
+				MockGetByID: func(id int64) (*db.User, error) {
+					return &db.User{ID: 1, Name: "unknwon"}, nil
+				},
+			},
+			expStatusCode: http.StatusOK,
+		},
+		{
+			name: "expired token",
+			token: func() string {
+				token, _ := lfsutil.NewAuthToken("super-secret", 1, 2, "download", -time.Hour)
+				return token
+			},
+			expStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong signature",
+			token: func() string {
+				token, _ := lfsutil.NewAuthToken("another-secret", 1, 2, "download", time.Hour)
+				return token
+			},
+			expStatusCode: http.StatusUnauthorized,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db.SetMockUsersStore(t, test.mockUsersStore)
+
+			r, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Authorization", "RemoteAuth "+test.token())
+
+			rr := httptest.NewRecorder()
+			m.ServeHTTP(rr, r)
+
+			resp := rr.Result()
+			assert.Equal(t, test.expStatusCode, resp.StatusCode)
+		})
+	}
+}
+
+// fakeOAuth2Verifier is a stub oauth2.Verifier for exercising the Bearer
+// scheme in authenticate() without a real issuer.
+type fakeOAuth2Verifier struct {
+	claims *oauth2.Claims
+	err    error
+}
+
+func (v *fakeOAuth2Verifier) Verify(context.Context, string) (*oauth2.Claims, error) {
+	return v.claims, v.err
+}
+
+// withOAuth2Verifier overrides the OAuth2 verifier for the duration of a
+// test and resets the cache afterwards.
+func withOAuth2Verifier(t *testing.T, verifier oauth2.Verifier, err error) {
+	before := newOAuth2Verifier
+	newOAuth2Verifier = func() (oauth2.Verifier, error) { return verifier, err }
+	oauth2VerifierCached = nil
+	t.Cleanup(func() {
+		newOAuth2Verifier = before
+		oauth2VerifierCached = nil
+	})
+}
+
+// Test_authenticate_Bearer covers the `Bearer` scheme used for OAuth2/OIDC
+// authentication, in addition to the Basic-auth cases covered by
+// Test_authenticate.
+func Test_authenticate_Bearer(t *testing.T) {
+	m := macaron.New()
+	m.Use(macaron.Renderer())
+	m.Get("/", authenticate(), func(w http.ResponseWriter, user *db.User) {
+		fmt.Fprintf(w, "ID: %d, Name: %s", user.ID, user.Name)
+	})
+
+	tests := []struct {
+		name           string
+		verifierClaims *oauth2.Claims
+		verifierErr    error
+		mockUsersStore *db.MockUsersStore
+		expStatusCode  int
+	}{
+		{
+			name:          "missing kid / unparsable token",
+			verifierErr:   errors.New("unknown kid"),
+			expStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "expired exp",
+			verifierErr:   errors.New("token is expired"),
+			expStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "unknown issuer",
+			verifierErr:   errors.New("unexpected issuer"),
+			expStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "successful bearer auth",
+			verifierClaims: &oauth2.Claims{Issuer: "https://issuer.example.com", Subject: "sub-1"},
+			mockUsersStore: &db.MockUsersStore{
+				MockGetByExternalID: func(provider, subject string) (*db.User, error) {
+					return &db.User{ID: 1, Name: "unknwon"}, nil
+				},
+			},
+			expStatusCode: http.StatusOK,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db.SetMockUsersStore(t, test.mockUsersStore)
+			withOAuth2Verifier(t, &fakeOAuth2Verifier{claims: test.verifierClaims, err: test.verifierErr}, nil)
+
+			r, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Authorization", "Bearer some-token")
+
+			rr := httptest.NewRecorder()
+			m.ServeHTTP(rr, r)
+
+			assert.Equal(t, test.expStatusCode, rr.Result().StatusCode)
+		})
+	}
+}
+
+// Test_authorize_TokenScope covers that authorize rejects an operation a
+// RemoteAuth token was not scoped for, or that was minted for a different
+// repository, independent of the actor's general repository permission.
+func Test_authorize_TokenScope(t *testing.T) {
+	const repoID = int64(42)
+
+	tests := []struct {
+		name          string
+		scope         *tokenScope
+		expStatusCode int
+	}{
+		{
+			name:          "operation does not cover desired access mode",
+			scope:         &tokenScope{repoID: repoID, operation: "download"},
+			expStatusCode: http.StatusNotFound,
+		},
+		{
+			name:          "token minted for a different repository",
+			scope:         &tokenScope{repoID: repoID + 1, operation: "upload"},
+			expStatusCode: http.StatusNotFound,
+		},
+		{
+			name:          "token matches repository and operation",
+			scope:         &tokenScope{repoID: repoID, operation: "upload"},
+			expStatusCode: http.StatusOK,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db.SetMockUsersStore(t, &db.MockUsersStore{
+				MockGetByUsername: func(username string) (*db.User, error) {
+					return &db.User{Name: username}, nil
+				},
+			})
+			db.SetMockReposStore(t, &db.MockReposStore{
+				MockGetByName: func(ownerID int64, name string) (*db.Repository, error) {
+					return &db.Repository{ID: repoID, Name: name}, nil
+				},
+			})
+			mockPerms := db.NewMockPermsStore()
+			mockPerms.AuthorizeFunc.SetDefaultReturn(true)
+			db.SetMockPermsStore(t, mockPerms)
+
+			m := macaron.New()
+			m.Use(macaron.Renderer())
+			m.Use(func(c *macaron.Context) {
+				mapActor(c, &db.User{})
+				c.Map(test.scope)
+			})
+			m.Get("/:username/:reponame", authorize(db.AccessModeWrite), func(w http.ResponseWriter) {
+				fmt.Fprint(w, "ok")
+			})
+
+			r, err := http.NewRequest("GET", "/owner/repo", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			m.ServeHTTP(rr, r)
+
+			assert.Equal(t, test.expStatusCode, rr.Result().StatusCode)
+		})
+	}
+}
+
+// Test_authorize_AccessTokenScope covers the interaction between a scoped
+// personal access token (mapped as accessTokenScopes by authenticate's Basic
+// auth path) and authorize's desired access mode.
+func Test_authorize_AccessTokenScope(t *testing.T) {
+	tests := []struct {
+		name          string
+		desired       db.AccessMode
+		scopes        accessTokenScopes
+		expStatusCode int
+	}{
+		{
+			name:          "token has lfs:read but write requested",
+			desired:       db.AccessModeWrite,
+			scopes:        accessTokenScopes{"lfs:read"},
+			expStatusCode: http.StatusNotFound,
+		},
+		{
+			name:          "token has lfs:write",
+			desired:       db.AccessModeWrite,
+			scopes:        accessTokenScopes{"lfs:write"},
+			expStatusCode: http.StatusOK,
+		},
+		{
+			name:          "password auth bypasses scope check",
+			desired:       db.AccessModeWrite,
+			scopes:        nil,
+			expStatusCode: http.StatusOK,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db.SetMockUsersStore(t, &db.MockUsersStore{
+				MockGetByUsername: func(username string) (*db.User, error) {
+					return &db.User{Name: username}, nil
+				},
+			})
+			db.SetMockReposStore(t, &db.MockReposStore{
+				MockGetByName: func(ownerID int64, name string) (*db.Repository, error) {
+					return &db.Repository{Name: name}, nil
+				},
+			})
+			mockPerms := db.NewMockPermsStore()
+			mockPerms.AuthorizeFunc.SetDefaultReturn(true)
+			db.SetMockPermsStore(t, mockPerms)
+
+			m := macaron.New()
+			m.Use(macaron.Renderer())
+			m.Use(func(c *macaron.Context) {
+				mapActor(c, &db.User{})
+				// Only a token-authenticated request maps accessTokenScopes;
+				// password auth leaves it unmapped, which is what makes the
+				// "password auth bypasses scope check" case representative.
+				if test.scopes != nil {
+					c.Map(test.scopes)
+				}
+			})
+			m.Get("/:username/:reponame", authorize(test.desired), func(w http.ResponseWriter) {
+				fmt.Fprint(w, "ok")
+			})
+
+			r, err := http.NewRequest("GET", "/owner/repo", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			m.ServeHTTP(rr, r)
+
+			assert.Equal(t, test.expStatusCode, rr.Result().StatusCode)
+		})
+	}
+}
+
+// selfSignedCert generates a throwaway self-signed certificate for exercising
+// the client-certificate authentication path.
+func selfSignedCert(t *testing.T) *x509.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test-service-account"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// Test_authenticate_ClientCert covers the mutual-TLS authentication path, in
+// addition to the Basic-auth cases covered by Test_authenticate.
+func Test_authenticate_ClientCert(t *testing.T) {
+	m := macaron.New()
+	m.Use(macaron.Renderer())
+	m.Get("/", authenticate(), func(w http.ResponseWriter, user *db.User) {
+		fmt.Fprintf(w, "ID: %d, Name: %s", user.ID, user.Name)
+	})
+
+	cert := selfSignedCert(t)
+
+	tests := []struct {
+		name           string
+		allowCertAuth  bool
+		setTLS         bool
+		mockUsersStore *db.MockUsersStore
+		expStatusCode  int
+	}{
+		{
+			name:          "no TLS",
+			allowCertAuth: true,
+			setTLS:        false,
+			expStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "unknown fingerprint",
+			allowCertAuth: true,
+			setTLS:        true,
+			mockUsersStore: &db.MockUsersStore{
+				MockGetByClientCertFingerprint: func(fingerprint string) (*db.User, error) {
+					return nil, db.ErrUserNotExist{}
+				},
+			},
+			expStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "valid fingerprint maps to user",
+			allowCertAuth: true,
+			setTLS:        true,
+			mockUsersStore: &db.MockUsersStore{
+				MockGetByClientCertFingerprint: func(fingerprint string) (*db.User, error) {
+					return &db.User{ID: 1, Name: "unknwon"}, nil
+				},
+			},
+			expStatusCode: http.StatusOK,
+		},
+		{
+			name:          "fingerprint present but feature disabled",
+			allowCertAuth: false,
+			setTLS:        true,
+			expStatusCode: http.StatusUnauthorized,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf.LFS.AllowClientCertAuth = test.allowCertAuth
+			defer func() { conf.LFS.AllowClientCertAuth = false }()
+
+			db.SetMockUsersStore(t, test.mockUsersStore)
+
+			r, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.setTLS {
+				r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+			}
+
+			rr := httptest.NewRecorder()
+			m.ServeHTTP(rr, r)
+
+			assert.Equal(t, test.expStatusCode, rr.Result().StatusCode)
+		})
+	}
+}
+
 func Test_authorize(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -219,6 +621,29 @@ func Test_authorize(t *testing.T) {
 			expStatusCode: http.StatusNotFound,
 		},
 
+		{
+			name:      "actor authorized for write but attempts force unlock without admin",
+			authroize: authorize(db.AccessModeAdmin),
+			mockUsersStore: &db.MockUsersStore{
+				MockGetByUsername: func(username string) (*db.User, error) {
+					return &db.User{Name: username}, nil
+				},
+			},
+			mockReposStore: &db.MockReposStore{
+				MockGetByName: func(ownerID int64, name string) (*db.Repository, error) {
+					return &db.Repository{Name: name}, nil
+				},
+			},
+			mockPermsStore: func() db.PermsStore {
+				mock := db.NewMockPermsStore()
+				mock.AuthorizeFunc.SetDefaultHook(func(ctx context.Context, userID int64, repoID int64, desired db.AccessMode, opts db.AccessModeOptions) bool {
+					return desired <= db.AccessModeWrite
+				})
+				return mock
+			},
+			expStatusCode: http.StatusNotFound,
+		},
+
 		{
 			name:      "actor is authorized",
 			authroize: authorize(db.AccessModeRead),
@@ -255,7 +680,7 @@ func Test_authorize(t *testing.T) {
 			m := macaron.New()
 			m.Use(macaron.Renderer())
 			m.Use(func(c *macaron.Context) {
-				c.Map(&db.User{})
+				mapActor(c, &db.User{})
 			})
 			m.Get("/:username/:reponame", test.authroize, func(w http.ResponseWriter, owner *db.User, repo *db.Repository) {
 				fmt.Fprintf(w, "owner.Name: %s, repo.Name: %s", owner.Name, repo.Name)
@@ -0,0 +1,49 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"gogs.io/gogs/internal/db"
+)
+
+// Dispatch parses the SSH original command line received from the client
+// and routes it to the matching handler. It currently only recognizes
+// `git-lfs-authenticate <owner>/<repo> <operation>`, which is what the
+// git-lfs client sends over SSH before falling back to the HTTP(S) batch
+// API; see
+// https://github.com/git-lfs/git-lfs/blob/master/docs/api/server-discovery.md#ssh.
+//
+// Dispatch is the routing layer only: it is meant to be called with the
+// SSH session's original command once this instance grows an SSH server
+// entry point that reads SSH_ORIGINAL_COMMAND and resolves the connecting
+// user, neither of which exists in this tree yet.
+func Dispatch(user *db.User, command string) (*LFSAuthenticateResponse, error) {
+	fields := strings.Fields(command)
+	if len(fields) != 3 || fields[0] != "git-lfs-authenticate" {
+		return nil, errors.Errorf("unsupported command %q", command)
+	}
+
+	parts := strings.SplitN(strings.TrimSuffix(fields[1], ".git"), "/", 2)
+	if len(parts) != 2 {
+		return nil, errors.Errorf("malformed repository path %q", fields[1])
+	}
+	ownerName, repoName := parts[0], parts[1]
+
+	owner, err := db.Users.GetByUsername(ownerName)
+	if err != nil {
+		return nil, errors.Wrap(err, "get owner")
+	}
+
+	repo, err := db.Repos.GetByName(owner.ID, repoName)
+	if err != nil {
+		return nil, errors.Wrap(err, "get repository")
+	}
+
+	return AuthenticateLFS(user, repo, fields[2])
+}
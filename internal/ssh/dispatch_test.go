@@ -0,0 +1,58 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gogs.io/gogs/internal/conf"
+	"gogs.io/gogs/internal/db"
+)
+
+func Test_Dispatch(t *testing.T) {
+	conf.LFS.JWTSecret = "secret"
+	conf.LFS.JWTExpiry = time.Hour
+	conf.Server.ExternalURL = "https://gogs.example.com/"
+
+	db.SetMockUsersStore(t, &db.MockUsersStore{
+		MockGetByUsername: func(username string) (*db.User, error) {
+			return &db.User{ID: 2, Name: username}, nil
+		},
+	})
+	db.SetMockReposStore(t, &db.MockReposStore{
+		MockGetByName: func(ownerID int64, name string) (*db.Repository, error) {
+			return &db.Repository{ID: 10, OwnerID: ownerID, OwnerName: "alice", Name: name}, nil
+		},
+	})
+
+	user := &db.User{ID: 1}
+
+	tests := []struct {
+		name    string
+		command string
+		wantErr bool
+	}{
+		{name: "not a git-lfs-authenticate command", command: "git-upload-pack alice/repo.git", wantErr: true},
+		{name: "missing operation", command: "git-lfs-authenticate alice/repo.git", wantErr: true},
+		{name: "malformed repository path", command: "git-lfs-authenticate repo.git download", wantErr: true},
+		{name: "unsupported operation", command: "git-lfs-authenticate alice/repo.git delete", wantErr: true},
+		{name: "valid download request", command: "git-lfs-authenticate alice/repo.git download", wantErr: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := Dispatch(user, test.command)
+			if test.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, resp)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "https://gogs.example.com/alice/repo.git/info/lfs", resp.Href)
+		})
+	}
+}
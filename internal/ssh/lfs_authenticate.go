@@ -0,0 +1,48 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gogs.io/gogs/internal/conf"
+	"gogs.io/gogs/internal/db"
+	"gogs.io/gogs/internal/lfsutil"
+)
+
+// LFSAuthenticateResponse is the JSON payload `git-lfs-authenticate` writes
+// to stdout, see
+// https://github.com/git-lfs/git-lfs/blob/master/docs/api/server-discovery.md#ssh.
+type LFSAuthenticateResponse struct {
+	Href      string            `json:"href"`
+	Header    map[string]string `json:"header,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// AuthenticateLFS is invoked by the `git-lfs-authenticate <repo> <operation>`
+// SSH command. It mints a short-lived token scoped to user's requested
+// operation against repo and returns the standard LFS SSH discovery
+// response pointing back at the HTTP(S) batch API.
+func AuthenticateLFS(user *db.User, repo *db.Repository, operation string) (*LFSAuthenticateResponse, error) {
+	if operation != "download" && operation != "upload" {
+		return nil, errors.Errorf("unsupported operation %q", operation)
+	}
+
+	expiry := conf.LFS.JWTExpiry
+	token, err := lfsutil.NewAuthToken(conf.LFS.JWTSecret, user.ID, repo.ID, operation, expiry)
+	if err != nil {
+		return nil, errors.Wrap(err, "new auth token")
+	}
+
+	return &LFSAuthenticateResponse{
+		Href: conf.Server.ExternalURL + repo.FullName() + ".git/info/lfs",
+		Header: map[string]string{
+			"Authorization": "RemoteAuth " + token,
+		},
+		ExpiresAt: time.Now().Add(expiry),
+	}, nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2020 The Gogs Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ssh
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gogs.io/gogs/internal/conf"
+	"gogs.io/gogs/internal/db"
+)
+
+func Test_AuthenticateLFS(t *testing.T) {
+	conf.LFS.JWTSecret = "secret"
+	conf.LFS.JWTExpiry = time.Hour
+	conf.Server.ExternalURL = "https://gogs.example.com/"
+
+	user := &db.User{ID: 1}
+	repo := &db.Repository{ID: 10, OwnerName: "alice", Name: "repo"}
+
+	t.Run("unsupported operation", func(t *testing.T) {
+		resp, err := AuthenticateLFS(user, repo, "delete")
+		assert.Nil(t, resp)
+		assert.Error(t, err)
+	})
+
+	t.Run("mints a scoped token", func(t *testing.T) {
+		resp, err := AuthenticateLFS(user, repo, "download")
+		assert.Nil(t, err)
+		assert.Equal(t, "https://gogs.example.com/alice/repo.git/info/lfs", resp.Href)
+		assert.True(t, strings.HasPrefix(resp.Header["Authorization"], "RemoteAuth "))
+		assert.False(t, resp.ExpiresAt.IsZero())
+	})
+}